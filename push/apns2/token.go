@@ -0,0 +1,59 @@
+package apns2
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenRefresh is how long an APNs provider JWT remains valid before we
+// mint a new one. Apple allows tokens up to one hour old; refreshing
+// somewhat earlier avoids edge-of-expiry rejections.
+const tokenRefresh = 50 * time.Minute
+
+// TokenAuth generates and caches APNs provider authentication tokens
+// (JWT, ES256) as an alternative to TLS client-certificate auth. See
+// Apple's "Establishing a token-based connection to APNs".
+type TokenAuth struct {
+	KeyID  string
+	TeamID string
+	Key    *ecdsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	generated time.Time
+}
+
+// NewTokenAuth creates a TokenAuth from an ECDSA private key (the .p8 key
+// downloaded from the Apple Developer portal), a 10-character key ID,
+// and the developer team ID.
+func NewTokenAuth(key *ecdsa.PrivateKey, keyID, teamID string) *TokenAuth {
+	return &TokenAuth{Key: key, KeyID: keyID, TeamID: teamID}
+}
+
+// Token returns a valid bearer token, minting a new one if the cached
+// token has expired or none has been generated yet.
+func (t *TokenAuth) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Since(t.generated) < tokenRefresh {
+		return t.token, nil
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": t.TeamID,
+		"iat": now.Unix(),
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	tok.Header["kid"] = t.KeyID
+	signed, err := tok.SignedString(t.Key)
+	if err != nil {
+		return "", fmt.Errorf("apns2: signing provider token: %w", err)
+	}
+	t.token = signed
+	t.generated = now
+	return t.token, nil
+}
@@ -2,6 +2,7 @@
 package certauth
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
@@ -20,6 +21,13 @@ var (
 	ErrMissingCert = errors.New("missing MDM certificate")
 )
 
+// CertRotationNotifier is the narrow slice of microwebhook.MicroWebhook
+// CertAuth needs, kept local so certauth doesn't take a hard dependency
+// on the microwebhook package.
+type CertRotationNotifier interface {
+	NotifyCertRotation(ctx context.Context, enrollID string) error
+}
+
 // normalize pulls out only the "device" ID (i.e. the "parent" of the)
 // MDM relationship regardless of enrollment type.
 func normalize(e *mdm.Enrollment) *mdm.EnrollID {
@@ -62,15 +70,49 @@ type CertAuth struct {
 	//
 	// WARNING: This allows MDM clients to spoof other MDM clients.
 	warnOnly bool
+
+	// revocationChecker, if set, is consulted to reject requests using
+	// a certificate the issuing CA has revoked. See WithRevocationChecker.
+	revocationChecker RevocationChecker
+	// revocationMode controls how a revoked certificate (or a
+	// RevocationChecker error) is handled. See WithRevocationMode.
+	revocationMode RevocationMode
+	// revocationCheckNew also checks revocation for new enrollments.
+	// See WithRevocationCheckNewEnrollments.
+	revocationCheckNew bool
+
+	// certRotationNotifier, if set, is notified when an existing
+	// enrollment (re-)associates with a new certificate hash, e.g.
+	// after an operator has cleared its prior association via
+	// AdminHandler. See WithCertRotationNotifier.
+	certRotationNotifier CertRotationNotifier
 }
 
-func NewCertAuthMiddleware(next service.CheckinAndCommandService, storage storage.CertAuthStore, logger log.Logger) *CertAuth {
-	return &CertAuth{
+// WithCertRotationNotifier notifies notifier when an existing
+// enrollment associates with a cert hash it wasn't already associated
+// with (i.e. a retroactive re-association, such as the one
+// AdminHandler's endpoints set up by clearing a prior association).
+// It is not called for a brand-new enrollment's first association.
+func WithCertRotationNotifier(notifier CertRotationNotifier) Option {
+	return func(s *CertAuth) {
+		s.certRotationNotifier = notifier
+	}
+}
+
+// Option configures a CertAuth returned by NewCertAuthMiddleware.
+type Option func(*CertAuth)
+
+func NewCertAuthMiddleware(next service.CheckinAndCommandService, storage storage.CertAuthStore, logger log.Logger, opts ...Option) *CertAuth {
+	s := &CertAuth{
 		next:       next,
 		logger:     logger,
 		normalizer: normalize,
 		storage:    storage,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func hashCert(cert *x509.Certificate) string {
@@ -87,6 +129,9 @@ func (s *CertAuth) associateNewEnrollment(r *mdm.Request) error {
 	if err := r.EnrollID.Validate(); err != nil {
 		return err
 	}
+	if err := s.checkRevocation(r, "new"); err != nil {
+		return err
+	}
 	hash := hashCert(r.Certificate)
 	if hasHash, err := s.storage.HasCertHash(r, hash); err != nil {
 		return err
@@ -131,6 +176,9 @@ func (s *CertAuth) validateAssociateExistingEnrollment(r *mdm.Request) error {
 	if err := r.EnrollID.Validate(); err != nil {
 		return err
 	}
+	if err := s.checkRevocation(r, "existing"); err != nil {
+		return err
+	}
 	hash := hashCert(r.Certificate)
 	if isAssoc, err := s.storage.IsCertHashAssociated(r, hash); err != nil {
 		return err
@@ -194,9 +242,26 @@ func (s *CertAuth) validateAssociateExistingEnrollment(r *mdm.Request) error {
 		"id", r.ID,
 		"hash", hash,
 	)
+	// Reaching here means this enrollment had no active association
+	// (checked above) and retroactive association is enabled: either a
+	// rotation after its prior association was cleared, or an existing
+	// enrollment's first association. Both are notified; they are not
+	// told apart here. Notify now, at the point the
+	// association actually happens, rather than when an operator merely
+	// clears the old one (which may never be followed by a check-in).
+	s.notifyCertRotation(r)
 	return nil
 }
 
+func (s *CertAuth) notifyCertRotation(r *mdm.Request) {
+	if s.certRotationNotifier == nil {
+		return
+	}
+	if err := s.certRotationNotifier.NotifyCertRotation(r.Context, r.ID); err != nil {
+		s.logger.Info("msg", "notifying cert rotation webhook", "id", r.ID, "err", err)
+	}
+}
+
 func (s *CertAuth) Authenticate(r *mdm.Request, m *mdm.Authenticate) error {
 	req := r.Clone()
 	req.EnrollID = s.normalizer(&m.Enrollment)
@@ -0,0 +1,126 @@
+package certauth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CRLChecker implements RevocationChecker by periodically fetching and
+// parsing CRLs from a fixed list of distribution points into an
+// in-memory serial-number set. Every fetched CRL's signature is
+// verified against issuer before its entries are trusted, so a
+// compromised or MITM'd distribution point can't hand back a forged
+// (e.g. empty) CRL to hide revocations.
+type CRLChecker struct {
+	client *http.Client
+	issuer *x509.Certificate
+	urls   []string
+
+	mu      sync.RWMutex
+	revoked map[string]struct{}
+
+	stop chan struct{}
+}
+
+// NewCRLChecker creates a CRLChecker that refreshes the given CRL
+// distribution point URLs every interval, rejecting any fetched CRL
+// that isn't signed by issuer (the CA that issued the certificates
+// these CRLs cover). If client is nil, http.DefaultClient is used. The
+// first fetch happens synchronously so Check has data to consult as
+// soon as NewCRLChecker returns; call Stop to end the periodic refresh
+// when finished.
+func NewCRLChecker(client *http.Client, issuer *x509.Certificate, urls []string, interval time.Duration) (*CRLChecker, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if issuer == nil {
+		return nil, errors.New("certauth: CRLChecker requires the issuing CA certificate")
+	}
+	c := &CRLChecker{
+		client:  client,
+		issuer:  issuer,
+		urls:    urls,
+		revoked: make(map[string]struct{}),
+		stop:    make(chan struct{}),
+	}
+	if err := c.refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	go c.refreshLoop(interval)
+	return c, nil
+}
+
+// Stop ends the periodic CRL refresh.
+func (c *CRLChecker) Stop() {
+	close(c.stop)
+}
+
+func (c *CRLChecker) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = c.refresh(context.Background())
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// refresh fetches and parses every configured CRL, replacing the
+// in-memory revoked-serial set only once all of them succeed, so a
+// transient fetch failure doesn't wipe out previously known revocations.
+func (c *CRLChecker) refresh(ctx context.Context) error {
+	revoked := make(map[string]struct{})
+	for _, url := range c.urls {
+		if err := c.fetchOne(ctx, url, revoked); err != nil {
+			return fmt.Errorf("fetching CRL %s: %w", url, err)
+		}
+	}
+	c.mu.Lock()
+	c.revoked = revoked
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *CRLChecker) fetchOne(ctx context.Context, url string, revoked map[string]struct{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return err
+	}
+	if err := crl.CheckSignatureFrom(c.issuer); err != nil {
+		return fmt.Errorf("verifying CRL signature: %w", err)
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		revoked[entry.SerialNumber.String()] = struct{}{}
+	}
+	return nil
+}
+
+// Check implements RevocationChecker.
+func (c *CRLChecker) Check(_ context.Context, cert *x509.Certificate) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, revoked := c.revoked[cert.SerialNumber.String()]
+	return revoked, nil
+}
@@ -0,0 +1,78 @@
+package certauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func generateTestCA(t *testing.T, cn string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		BasicConstraintsValid: true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func signTestCRL(t *testing.T, issuer *x509.Certificate, key *ecdsa.PrivateKey, revoked []x509.RevocationListEntry) *x509.RevocationList {
+	t.Helper()
+	tmpl := &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: revoked,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, issuer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crl
+}
+
+// TestCRLSignatureVerification exercises the check fetchOne relies on:
+// a CRL genuinely signed by the configured issuer verifies, and one
+// signed by any other key (e.g. a MITM'd or spoofed distribution
+// point) does not.
+func TestCRLSignatureVerification(t *testing.T) {
+	issuer, issuerKey := generateTestCA(t, "Real CA")
+	forger, forgerKey := generateTestCA(t, "Forged CA")
+
+	revoked := []x509.RevocationListEntry{{SerialNumber: big.NewInt(42), RevocationTime: time.Now()}}
+
+	genuine := signTestCRL(t, issuer, issuerKey, revoked)
+	if err := genuine.CheckSignatureFrom(issuer); err != nil {
+		t.Errorf("genuine CRL should verify against its issuer: %v", err)
+	}
+
+	forged := signTestCRL(t, forger, forgerKey, nil)
+	if err := forged.CheckSignatureFrom(issuer); err == nil {
+		t.Error("CRL signed by a different CA must not verify against the real issuer")
+	}
+}
@@ -0,0 +1,107 @@
+package certauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jessepeterson/nanomdm/log"
+	"github.com/jessepeterson/nanomdm/storage"
+)
+
+// AdminHandler serves the operator-driven cert-rotation endpoints
+// documented on ServeHTTP, backed by a storage.CertAuthAdminStore. That
+// store must be the same one backing the CertAuthStore passed to
+// NewCertAuthMiddleware (see storage/file.CertAuthStore), so that
+// clearing an association here is visible to CertAuth's own checks on
+// the device's next check-in.
+//
+// AdminHandler only clears associations; it does not itself emit the
+// mdm.CertRotation webhook event. The actual re-association (and the
+// notification that goes with it) happens later, when the device
+// checks in again, via CertAuth's WithCertRotationNotifier.
+type AdminHandler struct {
+	store  storage.CertAuthAdminStore
+	logger log.Logger
+}
+
+// NewAdminHandler creates an AdminHandler.
+func NewAdminHandler(store storage.CertAuthAdminStore, logger log.Logger) *AdminHandler {
+	return &AdminHandler{store: store, logger: logger}
+}
+
+// ServeHTTP implements, under the path prefix it is mounted at (e.g.
+// "/v1/certauth/"):
+//
+//	GET    {prefix}/{id}/cert-hashes       list cert-hash associations
+//	DELETE {prefix}/{id}/cert-hashes       clear all associations
+//	DELETE {prefix}/{id}/cert-hashes/{hash} clear one association
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enrollID, hash, ok := parseAdminPath(r.URL.Path)
+	if !ok || enrollID == "" {
+		http.Error(w, "invalid enrollment id", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && hash == "":
+		h.handleList(w, r, enrollID)
+	case r.Method == http.MethodDelete && hash == "":
+		h.handleDissociateAll(w, r, enrollID)
+	case r.Method == http.MethodDelete && hash != "":
+		h.handleDissociateOne(w, r, enrollID, hash)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// parseAdminPath extracts {id} and, optionally, {hash} from a path of
+// the form "{id}/cert-hashes[/{hash}]" (with any routing prefix already
+// stripped by the caller's mux).
+func parseAdminPath(path string) (id, hash string, ok bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "cert-hashes" {
+		return "", "", false
+	}
+	id = parts[0]
+	if len(parts) == 3 {
+		hash = parts[2]
+	}
+	return id, hash, true
+}
+
+func (h *AdminHandler) handleList(w http.ResponseWriter, r *http.Request, enrollID string) {
+	assocs, err := h.store.ListCertHashes(r.Context(), enrollID)
+	if err != nil {
+		h.logger.Info("msg", "listing cert hashes", "id", enrollID, "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(assocs); err != nil {
+		h.logger.Info("msg", "encoding cert hashes response", "id", enrollID, "err", err)
+	}
+}
+
+func (h *AdminHandler) handleDissociateAll(w http.ResponseWriter, r *http.Request, enrollID string) {
+	if err := h.store.DissociateAllCertHashes(r.Context(), enrollID); err != nil {
+		h.logger.Info("msg", "dissociating all cert hashes", "id", enrollID, "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) handleDissociateOne(w http.ResponseWriter, r *http.Request, enrollID, hash string) {
+	err := h.store.DissociateCertHash(r.Context(), enrollID, hash)
+	if err != nil {
+		status, msg := http.StatusInternalServerError, "internal error"
+		if errors.Is(err, ErrNoCertAssoc) || errors.Is(err, storage.ErrCertHashNotAssociated) {
+			status, msg = http.StatusNotFound, "cert hash not associated"
+		}
+		h.logger.Info("msg", "dissociating cert hash", "id", enrollID, "hash", hash, "err", err)
+		http.Error(w, msg, status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
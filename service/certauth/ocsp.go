@@ -0,0 +1,134 @@
+package certauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrNoOCSPResponder is returned when a certificate has no AIA OCSP
+// responder URL to query.
+var ErrNoOCSPResponder = errors.New("certauth: no OCSP responder in certificate")
+
+// ocspCacheEntry caches the result of one OCSP lookup until nextUpdate
+// (or, lacking one, for ocspDefaultCacheTTL).
+type ocspCacheEntry struct {
+	revoked    bool
+	nextUpdate time.Time
+}
+
+// ocspDefaultCacheTTL bounds how long an OCSP response is cached when the
+// responder did not supply a NextUpdate.
+const ocspDefaultCacheTTL = 1 * time.Hour
+
+// OCSPChecker implements RevocationChecker using the OCSP responder
+// embedded in a certificate's Authority Information Access extension,
+// caching responses in memory, keyed by serial number and issuer hash,
+// until their NextUpdate.
+type OCSPChecker struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]ocspCacheEntry
+}
+
+// NewOCSPChecker creates an OCSPChecker. If client is nil,
+// http.DefaultClient is used.
+func NewOCSPChecker(client *http.Client) *OCSPChecker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &OCSPChecker{client: client, cache: make(map[string]ocspCacheEntry)}
+}
+
+// cacheKey identifies a cached OCSP result by the certificate's serial
+// number and a hash of its issuer's raw subject, so that a reused serial
+// number from a different issuer can't be confused for a cache hit.
+func cacheKey(cert *x509.Certificate) string {
+	issuerHash := sha256.Sum256(cert.RawIssuer)
+	return cert.SerialNumber.String() + ":" + hex.EncodeToString(issuerHash[:])
+}
+
+// Check implements RevocationChecker.
+func (c *OCSPChecker) Check(ctx context.Context, cert *x509.Certificate) (bool, error) {
+	key := cacheKey(cert)
+	c.mu.Lock()
+	if entry, ok := c.cache[key]; ok && time.Now().Before(entry.nextUpdate) {
+		c.mu.Unlock()
+		return entry.revoked, nil
+	}
+	c.mu.Unlock()
+
+	if len(cert.OCSPServer) == 0 {
+		return false, ErrNoOCSPResponder
+	}
+	issuer, err := fetchIssuer(ctx, c.client, cert)
+	if err != nil {
+		return false, fmt.Errorf("fetching issuer for OCSP: %w", err)
+	}
+	reqBytes, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return false, fmt.Errorf("creating OCSP request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, cert.OCSPServer[0], bytes.NewReader(reqBytes),
+	)
+	if err != nil {
+		return false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("querying OCSP responder: %w", err)
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return false, err
+	}
+	ocspResp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return false, fmt.Errorf("parsing OCSP response: %w", err)
+	}
+	revoked := ocspResp.Status == ocsp.Revoked
+	nextUpdate := ocspResp.NextUpdate
+	if nextUpdate.IsZero() {
+		nextUpdate = time.Now().Add(ocspDefaultCacheTTL)
+	}
+	c.mu.Lock()
+	c.cache[key] = ocspCacheEntry{revoked: revoked, nextUpdate: nextUpdate}
+	c.mu.Unlock()
+	return revoked, nil
+}
+
+// fetchIssuer retrieves cert's issuer certificate via its AIA "CA
+// Issuers" URL.
+func fetchIssuer(ctx context.Context, client *http.Client, cert *x509.Certificate) (*x509.Certificate, error) {
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, errors.New("certificate has no CA Issuers URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cert.IssuingCertificateURL[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(body)
+}
@@ -0,0 +1,107 @@
+package certauth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/jessepeterson/nanomdm/mdm"
+)
+
+// RevocationMode controls how CertAuth reacts when a RevocationChecker
+// reports (or fails to determine) a certificate's revocation status.
+type RevocationMode int
+
+const (
+	// RevocationModeFail rejects the request whenever the checker
+	// reports the certificate revoked, or when the checker itself
+	// errors (e.g. the OCSP responder or CRL distribution point is
+	// unreachable). This is the strictest, and default, mode.
+	RevocationModeFail RevocationMode = iota
+
+	// RevocationModeWarn logs revoked certificates and checker errors
+	// but does not reject the request.
+	RevocationModeWarn
+
+	// RevocationModeSoftFail rejects the request when the certificate is
+	// definitively reported revoked, but only logs (and otherwise
+	// allows the request) when the checker itself errors, e.g. because
+	// the responder or CRL distribution point could not be reached.
+	RevocationModeSoftFail
+)
+
+// RevocationChecker determines whether cert has been revoked by its
+// issuing CA. Implementations may consult OCSP, CRLs, or some other
+// revocation source.
+type RevocationChecker interface {
+	Check(ctx context.Context, cert *x509.Certificate) (revoked bool, err error)
+}
+
+// WithRevocationChecker enables revocation enforcement using checker,
+// consulted in validateAssociateExistingEnrollment (and, if
+// WithRevocationCheckNewEnrollments is also given, associateNewEnrollment).
+func WithRevocationChecker(checker RevocationChecker) Option {
+	return func(s *CertAuth) {
+		s.revocationChecker = checker
+	}
+}
+
+// WithRevocationMode sets how CertAuth reacts to a revoked certificate or
+// a RevocationChecker error. Defaults to RevocationModeFail.
+func WithRevocationMode(mode RevocationMode) Option {
+	return func(s *CertAuth) {
+		s.revocationMode = mode
+	}
+}
+
+// WithRevocationCheckNewEnrollments also runs the RevocationChecker
+// during associateNewEnrollment (i.e. on initial Authenticate). This is
+// off by default since a CA will not typically have had the opportunity
+// to revoke a certificate it only just issued.
+func WithRevocationCheckNewEnrollments() Option {
+	return func(s *CertAuth) {
+		s.revocationCheckNew = true
+	}
+}
+
+// ErrCertRevoked is returned when a certificate has been revoked and
+// s.revocationMode does not permit continuing.
+var ErrCertRevoked = errors.New("certificate revoked")
+
+// checkRevocation consults s.revocationChecker, if configured, honoring
+// s.revocationMode. enrollment is "new" or "existing", used only for
+// logging.
+func (s *CertAuth) checkRevocation(r *mdm.Request, enrollment string) error {
+	if s.revocationChecker == nil {
+		return nil
+	}
+	if enrollment == "new" && !s.revocationCheckNew {
+		return nil
+	}
+	revoked, err := s.revocationChecker.Check(r.Context, r.Certificate)
+	if err != nil {
+		s.logger.Info(
+			"msg", "revocation check failed",
+			"enrollment", enrollment,
+			"id", r.ID,
+			"err", err,
+		)
+		if s.revocationMode == RevocationModeFail {
+			return fmt.Errorf("checking revocation: %w", err)
+		}
+		return nil
+	}
+	if !revoked {
+		return nil
+	}
+	s.logger.Info(
+		"msg", "certificate revoked",
+		"enrollment", enrollment,
+		"id", r.ID,
+	)
+	if s.revocationMode == RevocationModeWarn {
+		return nil
+	}
+	return ErrCertRevoked
+}
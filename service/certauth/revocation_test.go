@@ -0,0 +1,98 @@
+package certauth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/jessepeterson/nanomdm/log"
+	"github.com/jessepeterson/nanomdm/mdm"
+)
+
+// stubChecker is a RevocationChecker returning fixed results, for
+// exercising CertAuth.checkRevocation's RevocationMode handling without
+// a real OCSP responder or CRL distribution point.
+type stubChecker struct {
+	revoked bool
+	err     error
+}
+
+func (c *stubChecker) Check(_ context.Context, _ *x509.Certificate) (bool, error) {
+	return c.revoked, c.err
+}
+
+type nullLogger struct{}
+
+func (nullLogger) Debug(...interface{})            {}
+func (nullLogger) Info(...interface{})             {}
+func (l nullLogger) With(...interface{}) log.Logger { return l }
+
+func newTestCertAuth(checker RevocationChecker, mode RevocationMode, checkNew bool) *CertAuth {
+	return &CertAuth{
+		logger:             nullLogger{},
+		revocationChecker:  checker,
+		revocationMode:     mode,
+		revocationCheckNew: checkNew,
+	}
+}
+
+func TestCheckRevocationModes(t *testing.T) {
+	req := &mdm.Request{Context: context.Background(), Certificate: &x509.Certificate{}, ID: "test-id"}
+	checkerErr := errors.New("responder unreachable")
+
+	cases := []struct {
+		name    string
+		checker RevocationChecker
+		mode    RevocationMode
+		wantErr error
+		anyErr  bool // true if we only care that an error occurred, not which
+	}{
+		{"fail mode allows valid cert", &stubChecker{revoked: false}, RevocationModeFail, nil, false},
+		{"fail mode rejects revoked cert", &stubChecker{revoked: true}, RevocationModeFail, ErrCertRevoked, false},
+		{"fail mode rejects checker error", &stubChecker{err: checkerErr}, RevocationModeFail, nil, true},
+		{"warn mode allows revoked cert", &stubChecker{revoked: true}, RevocationModeWarn, nil, false},
+		{"warn mode allows checker error", &stubChecker{err: checkerErr}, RevocationModeWarn, nil, false},
+		{"soft-fail mode rejects revoked cert", &stubChecker{revoked: true}, RevocationModeSoftFail, ErrCertRevoked, false},
+		{"soft-fail mode allows checker error", &stubChecker{err: checkerErr}, RevocationModeSoftFail, nil, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestCertAuth(c.checker, c.mode, false)
+			err := s.checkRevocation(req, "existing")
+			if c.anyErr {
+				if err == nil {
+					t.Fatal("checkRevocation() = nil, want a non-nil error")
+				}
+				return
+			}
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("checkRevocation() error = %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckRevocationSkippedWithoutChecker(t *testing.T) {
+	s := newTestCertAuth(nil, RevocationModeFail, false)
+	req := &mdm.Request{Context: context.Background(), Certificate: &x509.Certificate{}}
+	if err := s.checkRevocation(req, "existing"); err != nil {
+		t.Fatalf("checkRevocation() with no checker configured = %v, want nil", err)
+	}
+}
+
+func TestCheckRevocationSkipsNewEnrollmentsByDefault(t *testing.T) {
+	s := newTestCertAuth(&stubChecker{revoked: true}, RevocationModeFail, false)
+	req := &mdm.Request{Context: context.Background(), Certificate: &x509.Certificate{}}
+	if err := s.checkRevocation(req, "new"); err != nil {
+		t.Fatalf("checkRevocation() for new enrollment without WithRevocationCheckNewEnrollments = %v, want nil", err)
+	}
+}
+
+func TestCheckRevocationChecksNewEnrollmentsWhenEnabled(t *testing.T) {
+	s := newTestCertAuth(&stubChecker{revoked: true}, RevocationModeFail, true)
+	req := &mdm.Request{Context: context.Background(), Certificate: &x509.Certificate{}}
+	if err := s.checkRevocation(req, "new"); !errors.Is(err, ErrCertRevoked) {
+		t.Fatalf("checkRevocation() = %v, want ErrCertRevoked", err)
+	}
+}
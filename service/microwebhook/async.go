@@ -0,0 +1,218 @@
+package microwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// maxBatchSize caps how many queued events a single batch POST will carry,
+// regardless of how many accumulate within the batch window.
+const maxBatchSize = 100
+
+// Stats reports MicroWebhook's async queue and delivery counters.
+type Stats struct {
+	QueueDepth    int
+	Enqueued      uint64
+	Delivered     uint64
+	Dropped       uint64
+	DeadLettered  uint64
+	DeliveryError uint64
+}
+
+// enqueue hands ev to the async worker pool, or delivers it synchronously
+// if async mode isn't enabled. It never blocks past the queue's capacity:
+// if the queue is full the event is dropped (and, if configured,
+// dead-lettered) rather than backing up the caller's MDM request.
+func (w *MicroWebhook) enqueue(ctx context.Context, event *Event) error {
+	if w.queue == nil {
+		return w.deliver(ctx, event)
+	}
+	if atomic.LoadInt32(&w.closed) != 0 {
+		return errQueueSaturated
+	}
+	select {
+	case w.queue <- event:
+		atomic.AddUint64(&w.stats.Enqueued, 1)
+		return nil
+	default:
+		atomic.AddUint64(&w.stats.Dropped, 1)
+		if w.logger != nil {
+			w.logger.Info(
+				"msg", "webhook queue saturated, dropping event",
+				"topic", event.Topic,
+				"event_id", event.EventID,
+				"queue_size", cap(w.queue),
+			)
+		}
+		if w.deadLetterStore != nil {
+			if jsonBytes, err := json.MarshalIndent(event, "", "\t"); err == nil {
+				_ = w.deadLetterStore.StoreDeadLetter(detach(ctx), event.EventID, jsonBytes, errQueueSaturated)
+				atomic.AddUint64(&w.stats.DeadLettered, 1)
+			}
+		}
+		return errQueueSaturated
+	}
+}
+
+// startWorkers launches w.workers goroutines that drain w.queue, batching
+// events that arrive within w.batchWindow of the first event of a batch
+// when a batch URL is configured.
+func (w *MicroWebhook) startWorkers() {
+	w.wg.Add(w.workers)
+	for i := 0; i < w.workers; i++ {
+		go w.runWorker()
+	}
+}
+
+func (w *MicroWebhook) runWorker() {
+	defer w.wg.Done()
+	for {
+		ev, ok := <-w.queue
+		if !ok {
+			return
+		}
+		// Counted as in-flight from the moment it's dequeued, not from
+		// when delivery actually starts: a dequeued event sits outside
+		// w.queue for the whole fillBatch accumulation window too, and
+		// Flush must not see it as drained until deliverBatch returns.
+		atomic.AddInt32(&w.inFlight, 1)
+		batch := []*Event{ev}
+		if w.batchURL != "" && w.batchWindow > 0 {
+			batch = w.fillBatch(batch)
+		}
+		w.deliverBatch(batch)
+		atomic.AddInt32(&w.inFlight, -len(batch))
+	}
+}
+
+// fillBatch accumulates additional already-queued events onto batch until
+// w.batchWindow elapses, the queue is empty, or maxBatchSize is reached.
+// Each event it dequeues is counted as in-flight immediately, the same
+// as batch's initial event in runWorker, so Flush can't observe one
+// sitting in neither w.queue nor w.inFlight.
+func (w *MicroWebhook) fillBatch(batch []*Event) []*Event {
+	timer := time.NewTimer(w.batchWindow)
+	defer timer.Stop()
+	for len(batch) < maxBatchSize {
+		select {
+		case ev, ok := <-w.queue:
+			if !ok {
+				return batch
+			}
+			atomic.AddInt32(&w.inFlight, 1)
+			batch = append(batch, ev)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// deliverBatch delivers a batch of one or more events: single events (or
+// batches when no WithBatchURL is configured) are POSTed individually to
+// preserve backward compatibility with receivers that only understand a
+// single event body; multiple events are POSTed as a JSON array to
+// w.batchURL.
+func (w *MicroWebhook) deliverBatch(batch []*Event) {
+	ctx := context.Background()
+	if w.batchURL == "" || len(batch) == 1 {
+		for _, ev := range batch {
+			if err := w.deliver(ctx, ev); err != nil {
+				atomic.AddUint64(&w.stats.DeliveryError, 1)
+				if w.logger != nil {
+					w.logger.Info("msg", "webhook delivery failed", "event_id", ev.EventID, "err", err)
+				}
+				continue
+			}
+			atomic.AddUint64(&w.stats.Delivered, 1)
+		}
+		return
+	}
+	jsonBytes, err := json.MarshalIndent(batch, "", "\t")
+	if err != nil {
+		if w.logger != nil {
+			w.logger.Info("msg", "marshaling webhook batch failed", "err", err, "batch_size", len(batch))
+		}
+		return
+	}
+	// Unlimited budget: this already runs off the caller's goroutine, so
+	// there's no deadline to protect by cutting retries short.
+	lastErr := w.postWithRetry(ctx, w.batchURL, jsonBytes, 0, func(err error, resp *http.Response) error {
+		return batchError(resp, err, w.batchURL, len(batch))
+	})
+	if lastErr == nil {
+		atomic.AddUint64(&w.stats.Delivered, uint64(len(batch)))
+		return
+	}
+	atomic.AddUint64(&w.stats.DeliveryError, uint64(len(batch)))
+	if w.logger != nil {
+		w.logger.Info("msg", "webhook batch delivery failed", "err", lastErr, "batch_size", len(batch))
+	}
+	if w.deadLetterStore != nil {
+		for _, ev := range batch {
+			evBytes, err := json.MarshalIndent(ev, "", "\t")
+			if err != nil {
+				continue
+			}
+			_ = w.deadLetterStore.StoreDeadLetter(ctx, ev.EventID, evBytes, lastErr)
+			atomic.AddUint64(&w.stats.DeadLettered, 1)
+		}
+	}
+}
+
+// Stats returns a snapshot of the async queue depth and delivery counters.
+// Stats always returns a zero-value Stats when async mode isn't enabled.
+func (w *MicroWebhook) Stats() Stats {
+	return Stats{
+		QueueDepth:    len(w.queue),
+		Enqueued:      atomic.LoadUint64(&w.stats.Enqueued),
+		Delivered:     atomic.LoadUint64(&w.stats.Delivered),
+		Dropped:       atomic.LoadUint64(&w.stats.Dropped),
+		DeadLettered:  atomic.LoadUint64(&w.stats.DeadLettered),
+		DeliveryError: atomic.LoadUint64(&w.stats.DeliveryError),
+	}
+}
+
+// Flush blocks until the async queue has drained and every dequeued
+// event has finished delivering (including any mid-retry-backoff
+// worker), or ctx is canceled, whichever comes first. It is a no-op
+// when async mode isn't enabled.
+func (w *MicroWebhook) Flush(ctx context.Context) error {
+	if w.queue == nil {
+		return nil
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for len(w.queue) > 0 || atomic.LoadInt32(&w.inFlight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close stops accepting new async events, waits for queued events to be
+// delivered, and returns once all worker goroutines have exited. It is a
+// no-op when async mode isn't enabled.
+func (w *MicroWebhook) Close() error {
+	if w.queue == nil {
+		return nil
+	}
+	w.closeOnce.Do(func() {
+		atomic.StoreInt32(&w.closed, 1)
+		close(w.queue)
+	})
+	w.wg.Wait()
+	return nil
+}
+
+var errQueueSaturated = &queueSaturatedError{}
+
+type queueSaturatedError struct{}
+
+func (*queueSaturatedError) Error() string { return "microwebhook: async queue saturated, event dropped" }
@@ -0,0 +1,22 @@
+package microwebhook
+
+import (
+	"context"
+	"time"
+)
+
+// NotifyCertRotation emits an "mdm.CertRotation" event for enrollID when
+// it actually (re-)associates with a new cert hash, e.g. following an
+// operator clearing its prior association via certauth.AdminHandler. It
+// satisfies certauth.CertRotationNotifier.
+func (w *MicroWebhook) NotifyCertRotation(ctx context.Context, enrollID string) error {
+	ev := &Event{
+		Topic:        "mdm.CertRotation",
+		CreatedAt:    time.Now(),
+		EnrollmentID: enrollID,
+		CheckinEvent: &CheckinEvent{
+			EnrollmentID: enrollID,
+		},
+	}
+	return w.enqueue(ctx, ev)
+}
@@ -0,0 +1,123 @@
+package microwebhook
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/micromdm/nanomdm/log"
+	"github.com/micromdm/nanomdm/storage"
+)
+
+// Option configures a MicroWebhook returned by New.
+type Option func(*MicroWebhook)
+
+// WithRetryPolicy overrides the default retry/backoff behavior used when
+// delivering webhook events.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(w *MicroWebhook) {
+		w.retryPolicy = policy
+	}
+}
+
+// WithDeadLetterStore configures a store that receives events which could
+// not be delivered after all retries were exhausted.
+func WithDeadLetterStore(store storage.WebhookDeadLetterStore) Option {
+	return func(w *MicroWebhook) {
+		w.deadLetterStore = store
+	}
+}
+
+// WithHTTPClient overrides the default HTTP client used to deliver webhook
+// events.
+func WithHTTPClient(client *http.Client) Option {
+	return func(w *MicroWebhook) {
+		w.client = client
+	}
+}
+
+// WithTimeout sets the per-delivery-attempt timeout. This timeout is
+// independent of the MDM request context: a slow or hanging webhook
+// receiver will not hold open the MDM check-in or command-result
+// response. Defaults to 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(w *MicroWebhook) {
+		w.timeout = d
+	}
+}
+
+// WithSyncBudget bounds how long a synchronous (non-async) delivery may
+// spend retrying before giving up and returning to the caller,
+// independent of how many attempts the retry policy would otherwise
+// allow. This keeps a flaky webhook receiver from blocking the MDM
+// check-in or command-result response for the full retry/backoff
+// duration. It has no effect when WithAsync is enabled, since delivery
+// there already happens off the request goroutine. Defaults to 5
+// seconds; a zero or negative value disables the budget (not
+// recommended).
+func WithSyncBudget(d time.Duration) Option {
+	return func(w *MicroWebhook) {
+		w.syncBudget = d
+	}
+}
+
+// WithSigningSecret signs every outbound webhook body with
+// HMAC-SHA256(secret, timestamp + "." + body), set as the
+// X-NanoMDM-Timestamp and X-NanoMDM-Signature headers. Pass more than one
+// secret to support rotation: requests are signed with all of them so
+// receivers can validate against either the old or new secret until the
+// rotation is complete.
+func WithSigningSecret(secret ...[]byte) Option {
+	return func(w *MicroWebhook) {
+		w.signer = NewHMACSigner(secret...)
+	}
+}
+
+// WithSigner overrides the signing scheme entirely, e.g. to sign with
+// Ed25519 instead of the default HMAC-SHA256.
+func WithSigner(signer Signer) Option {
+	return func(w *MicroWebhook) {
+		w.signer = signer
+	}
+}
+
+// WithSigningKeyID sets an identifier for the active signing key, sent as
+// the X-NanoMDM-Key-Id header so receivers can select the right key
+// during rotation without trying every known secret.
+func WithSigningKeyID(keyID string) Option {
+	return func(w *MicroWebhook) {
+		w.keyID = keyID
+	}
+}
+
+// WithAsync backs MicroWebhook with a bounded worker pool: calls enqueue
+// the event and return immediately, instead of blocking the calling MDM
+// request goroutine on the webhook POST. workers sets how many
+// goroutines deliver queued events concurrently; queueSize bounds how
+// many events may be pending before new ones are dropped (and, if
+// configured, dead-lettered) to avoid unbounded memory growth during an
+// outage. Call Flush or Close to drain the queue, e.g. on shutdown.
+func WithAsync(workers, queueSize int) Option {
+	return func(w *MicroWebhook) {
+		w.workers = workers
+		w.queue = make(chan *Event, queueSize)
+	}
+}
+
+// WithBatchURL enables micro-batching in async mode: events that arrive
+// within window of the first one a worker dequeues are POSTed together as a JSON array to
+// url, falling back to per-event POSTs to the original url when only a
+// single event is ready. Has no effect unless WithAsync is also given.
+func WithBatchURL(url string, window time.Duration) Option {
+	return func(w *MicroWebhook) {
+		w.batchURL = url
+		w.batchWindow = window
+	}
+}
+
+// WithLogger sets a logger used for structured logging of async delivery
+// failures and queue backpressure (dropped events).
+func WithLogger(logger log.Logger) Option {
+	return func(w *MicroWebhook) {
+		w.logger = logger
+	}
+}
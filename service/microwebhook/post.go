@@ -3,37 +3,175 @@ package microwebhook
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func postWebhookEvent(
-	ctx context.Context,
-	client *http.Client,
-	url string,
-	event *Event,
-) error {
+// deliver marshals event and attempts to deliver it to w.url, retrying
+// according to w.retryPolicy on retryable failures. The per-attempt
+// timeout is independent of ctx's deadline (though ctx cancellation still
+// stops further retries) so that a caller's request context does not need to
+// stay alive for the full duration of retries. Outside async mode,
+// retrying is further bounded by w.syncBudget so a flaky receiver can't
+// hold open the caller's (e.g. the MDM request's) response indefinitely.
+func (w *MicroWebhook) deliver(ctx context.Context, event *Event) error {
 	jsonBytes, err := json.MarshalIndent(event, "", "\t")
 	if err != nil {
 		return err
 	}
+	var budget time.Duration
+	if w.queue == nil {
+		budget = w.syncBudget
+	}
+	lastErr := w.postWithRetry(ctx, w.url, jsonBytes, budget, func(err error, resp *http.Response) error {
+		return postError(resp, err, w.url, event)
+	})
+	if lastErr == nil {
+		return nil
+	}
+	if w.deadLetterStore != nil {
+		if dlErr := w.deadLetterStore.StoreDeadLetter(detach(ctx), event.EventID, jsonBytes, lastErr); dlErr != nil {
+			return fmt.Errorf("dead-lettering webhook event after delivery failure (%v): %w", lastErr, dlErr)
+		}
+	}
+	return lastErr
+}
+
+// detach returns a context that keeps parent's values but carries neither
+// its deadline nor its cancellation, so that in-flight attempts aren't
+// bound to a caller's (e.g. the MDM request's) deadline.
+func detach(parent context.Context) context.Context {
+	return context.WithoutCancel(parent)
+}
+
+// postWithRetry POSTs jsonBytes to url, retrying per w.retryPolicy on
+// retryable failures (honoring any Retry-After header) until a 200 is
+// received, a terminal failure occurs, retries are exhausted, ctx is
+// canceled, or (if budget is non-zero) retrying further would push the
+// total elapsed time past budget. describe formats the error to report
+// for a given attempt's (err, resp) outcome. Every response it receives
+// is drained and closed before postWithRetry returns or retries.
+func (w *MicroWebhook) postWithRetry(
+	ctx context.Context,
+	url string,
+	jsonBytes []byte,
+	budget time.Duration,
+	describe func(err error, resp *http.Response) error,
+) error {
+	start := time.Now()
+	var lastErr error
+	attempts := w.retryPolicy.MaxRetries + 1
+	for attempt := 1; attempt <= attempts; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(detach(ctx), w.timeout)
+		resp, err := doPostWebhookEvent(attemptCtx, w.client, url, jsonBytes, w.signer, w.keyID)
+		cancel()
+		if err == nil && resp.StatusCode == http.StatusOK {
+			drainAndClose(resp)
+			return nil
+		}
+		lastErr = describe(err, resp)
+		retryable := isRetryable(err, resp)
+		delay := w.retryPolicy.backoff(attempt, retryAfter(resp))
+		drainAndClose(resp)
+		if attempt == attempts || !retryable {
+			return lastErr
+		}
+		if budget > 0 && time.Since(start)+delay > budget {
+			return lastErr
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+// drainAndClose discards any remaining response body and closes it so
+// that a delivery attempt (and, over the course of retries, every
+// attempt) doesn't leak the underlying connection. It is a no-op if
+// resp is nil.
+func drainAndClose(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func doPostWebhookEvent(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	jsonBytes []byte,
+	signer Signer,
+	keyID string,
+) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	resp, err := client.Do(req)
+	if signer != nil {
+		if err := setSignatureHeaders(req, signer, jsonBytes, keyID); err != nil {
+			return nil, fmt.Errorf("signing webhook event: %w", err)
+		}
+	}
+	return client.Do(req)
+}
+
+// setSignatureHeaders signs body and sets the X-NanoMDM-Timestamp and
+// X-NanoMDM-Signature headers (plus X-NanoMDM-Key-Id, if keyID is set) on
+// req, following the scheme used by GitHub/Stripe webhooks.
+func setSignatureHeaders(req *http.Request, signer Signer, body []byte, keyID string) error {
+	ts := time.Now()
+	sigs, err := signer.Sign(body, ts)
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("unexpected HTTP status %d %s for webhook_url: %s, " +
-			"topic=%s, command_uuid=%s, enrollment_id=%s, event_id=%s, " +
-			"helpful headers? X-Forwarded-For: %s, X-Forwarded-Proto: %s ",
-			resp.StatusCode, resp.Status, url,
-			event.Topic, event.CommandUUID, event.EnrollmentID, event.EventID,
-			resp.Header["X-Forwarded-For"], resp.Header["X-Forwarded-Proto"])
+	parts := make([]string, 0, len(sigs)+1)
+	parts = append(parts, "t="+strconv.FormatInt(ts.Unix(), 10))
+	for _, sig := range sigs {
+		parts = append(parts, sig.Version+"="+hex.EncodeToString(sig.Value))
+	}
+	req.Header.Set("X-NanoMDM-Timestamp", strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set("X-NanoMDM-Signature", strings.Join(parts, ","))
+	if keyID != "" {
+		req.Header.Set("X-NanoMDM-Key-Id", keyID)
 	}
 	return nil
 }
+
+// batchError formats the error to report for a failed batch POST (used
+// by deliverBatch's retry loop in place of postError, which needs a
+// single Event's fields).
+func batchError(resp *http.Response, err error, url string, batchSize int) error {
+	if err != nil {
+		return fmt.Errorf("posting webhook batch (size=%d) to %s: %w", batchSize, url, err)
+	}
+	return fmt.Errorf("unexpected HTTP status %d %s for webhook batch (size=%d) to %s",
+		resp.StatusCode, resp.Status, batchSize, url)
+}
+
+func postError(resp *http.Response, err error, url string, event *Event) error {
+	if err != nil {
+		return fmt.Errorf("posting webhook event to %s, topic=%s, command_uuid=%s, "+
+			"enrollment_id=%s, event_id=%s: %w",
+			url, event.Topic, event.CommandUUID, event.EnrollmentID, event.EventID, err)
+	}
+	return fmt.Errorf("unexpected HTTP status %d %s for webhook_url: %s, "+
+		"topic=%s, command_uuid=%s, enrollment_id=%s, event_id=%s, "+
+		"helpful headers? X-Forwarded-For: %s, X-Forwarded-Proto: %s ",
+		resp.StatusCode, resp.Status, url,
+		event.Topic, event.CommandUUID, event.EnrollmentID, event.EventID,
+		resp.Header["X-Forwarded-For"], resp.Header["X-Forwarded-Proto"])
+}
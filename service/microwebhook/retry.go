@@ -0,0 +1,111 @@
+package microwebhook
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how deliver (via postWithRetry) retries a failed delivery
+// before giving up and, if configured, spooling the event to a dead-letter
+// store.
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts made after the
+	// initial delivery attempt. A value of 0 disables retries.
+	MaxRetries int
+
+	// BaseDelay is the delay before the first retry. Subsequent retries
+	// back off exponentially from this value.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed backoff, regardless of attempt count or
+	// any Retry-After header.
+	MaxDelay time.Duration
+
+	// Multiplier is applied to BaseDelay for each subsequent retry.
+	// If zero, 2.0 is used.
+	Multiplier float64
+
+	// Jitter, if true, randomizes each computed delay between 50% and
+	// 100% of its value to avoid thundering-herd retries.
+	Jitter bool
+}
+
+// DefaultRetryPolicy is used when New is not given a WithRetryPolicy option.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  1 * time.Second,
+	MaxDelay:   30 * time.Second,
+	Multiplier: 2.0,
+	Jitter:     true,
+}
+
+// backoff computes the delay before retry attempt n (1-indexed). retryAfter,
+// if non-zero, overrides the computed exponential delay (but is still
+// capped by MaxDelay).
+func (p *RetryPolicy) backoff(n int, retryAfter time.Duration) time.Duration {
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2.0
+	}
+	delay := retryAfter
+	if delay <= 0 {
+		delay = p.BaseDelay
+		for i := 1; i < n; i++ {
+			delay = time.Duration(float64(delay) * mult)
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter && retryAfter <= 0 && delay > 0 {
+		half := delay / 2
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return delay
+}
+
+// isRetryable reports whether err (from an HTTP round trip) or resp (a
+// completed, non-2xx response) should be retried. A non-nil err is
+// retried only when it's a net.Error (dial timeouts, connection resets,
+// DNS failures, etc.); errors that a retry can't fix, such as a TLS
+// certificate validation failure or "stopped after N redirects", are
+// treated as terminal.
+func isRetryable(err error, resp *http.Response) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// retryAfter parses a Retry-After header, supporting both delay-seconds
+// and HTTP-date forms. It returns 0 if the header is absent or invalid.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
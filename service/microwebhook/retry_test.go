@@ -0,0 +1,73 @@
+package microwebhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeNetError is a minimal net.Error for exercising isRetryable's
+// classification without dialing anything real.
+type fakeNetError struct{ timeout bool }
+
+func (e *fakeNetError) Error() string   { return "fake net error" }
+func (e *fakeNetError) Timeout() bool   { return e.timeout }
+func (e *fakeNetError) Temporary() bool { return e.timeout }
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		resp *http.Response
+		want bool
+	}{
+		{"network error", &fakeNetError{timeout: true}, nil, true},
+		{"generic non-network error", errors.New("boom"), nil, false},
+		{"nil err and nil resp", nil, nil, false},
+		{"5xx", nil, &http.Response{StatusCode: http.StatusBadGateway}, true},
+		{"429", nil, &http.Response{StatusCode: http.StatusTooManyRequests}, true},
+		{"408", nil, &http.Response{StatusCode: http.StatusRequestTimeout}, true},
+		{"4xx other than 408/429", nil, &http.Response{StatusCode: http.StatusBadRequest}, false},
+		{"2xx", nil, &http.Response{StatusCode: http.StatusOK}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isRetryable(c.err, c.resp); got != c.want {
+				t.Errorf("isRetryable() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+	cases := map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		5: 10 * time.Second, // capped by MaxDelay
+	}
+	for n, want := range cases {
+		if got := p.backoff(n, 0); got != want {
+			t.Errorf("backoff(%d, 0) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2, Jitter: true}
+	for i := 0; i < 50; i++ {
+		got := p.backoff(1, 0)
+		if got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("jittered backoff %v outside [50%%,100%%] of base delay", got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffRetryAfterCapped(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	if got := p.backoff(1, 20*time.Second); got != 5*time.Second {
+		t.Errorf("backoff(1, 20s) = %v, want MaxDelay %v", got, 5*time.Second)
+	}
+}
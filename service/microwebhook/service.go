@@ -3,24 +3,65 @@ package microwebhook
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/micromdm/nanomdm/log"
 	"github.com/micromdm/nanomdm/mdm"
 	"github.com/micromdm/nanomdm/storage"
 )
 
+// defaultTimeout bounds a single webhook delivery attempt when no
+// WithTimeout option is given.
+const defaultTimeout = 30 * time.Second
+
+// defaultSyncBudget bounds how long a synchronous (non-async) delivery
+// may retry before giving up, so a flaky receiver can't hold open the
+// calling MDM check-in or command-result response for the retry
+// policy's full worst-case duration. It has no effect in async mode.
+const defaultSyncBudget = 5 * time.Second
+
 type MicroWebhook struct {
 	url    string
 	client *http.Client
 	store  storage.TokenUpdateTallyStore
+
+	timeout         time.Duration
+	retryPolicy     RetryPolicy
+	syncBudget      time.Duration
+	deadLetterStore storage.WebhookDeadLetterStore
+
+	signer Signer
+	keyID  string
+
+	logger      log.Logger
+	workers     int
+	queue       chan *Event
+	batchURL    string
+	batchWindow time.Duration
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+	closed      int32
+	inFlight    int32
+	stats       Stats
 }
 
-func New(url string, store storage.TokenUpdateTallyStore) *MicroWebhook {
-	return &MicroWebhook{
-		url:    url,
-		client: http.DefaultClient,
-		store:  store,
+func New(url string, store storage.TokenUpdateTallyStore, opts ...Option) *MicroWebhook {
+	w := &MicroWebhook{
+		url:         url,
+		client:      http.DefaultClient,
+		store:       store,
+		timeout:     defaultTimeout,
+		retryPolicy: DefaultRetryPolicy,
+		syncBudget:  defaultSyncBudget,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.queue != nil {
+		w.startWorkers()
 	}
+	return w
 }
 
 func (w *MicroWebhook) Authenticate(r *mdm.Request, m *mdm.Authenticate) error {
@@ -36,7 +77,7 @@ func (w *MicroWebhook) Authenticate(r *mdm.Request, m *mdm.Authenticate) error {
 			Params:       r.Params,
 		},
 	}
-	return postWebhookEvent(r.Context, w.client, w.url, ev)
+	return w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) TokenUpdate(r *mdm.Request, m *mdm.TokenUpdate) error {
@@ -59,7 +100,7 @@ func (w *MicroWebhook) TokenUpdate(r *mdm.Request, m *mdm.TokenUpdate) error {
 		}
 		ev.CheckinEvent.TokenUpdateTally = &tally
 	}
-	return postWebhookEvent(r.Context, w.client, w.url, ev)
+	return w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) CheckOut(r *mdm.Request, m *mdm.CheckOut) error {
@@ -75,7 +116,7 @@ func (w *MicroWebhook) CheckOut(r *mdm.Request, m *mdm.CheckOut) error {
 			Params:       r.Params,
 		},
 	}
-	return postWebhookEvent(r.Context, w.client, w.url, ev)
+	return w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) UserAuthenticate(r *mdm.Request, m *mdm.UserAuthenticate) ([]byte, error) {
@@ -91,7 +132,7 @@ func (w *MicroWebhook) UserAuthenticate(r *mdm.Request, m *mdm.UserAuthenticate)
 			Params:       r.Params,
 		},
 	}
-	return nil, postWebhookEvent(r.Context, w.client, w.url, ev)
+	return nil, w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) SetBootstrapToken(r *mdm.Request, m *mdm.SetBootstrapToken) error {
@@ -107,7 +148,7 @@ func (w *MicroWebhook) SetBootstrapToken(r *mdm.Request, m *mdm.SetBootstrapToke
 			Params:       r.Params,
 		},
 	}
-	return postWebhookEvent(r.Context, w.client, w.url, ev)
+	return w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) GetBootstrapToken(r *mdm.Request, m *mdm.GetBootstrapToken) (*mdm.BootstrapToken, error) {
@@ -123,7 +164,7 @@ func (w *MicroWebhook) GetBootstrapToken(r *mdm.Request, m *mdm.GetBootstrapToke
 			Params:       r.Params,
 		},
 	}
-	return nil, postWebhookEvent(r.Context, w.client, w.url, ev)
+	return nil, w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) CommandAndReportResults(r *mdm.Request, results *mdm.CommandResults) (*mdm.Command, error) {
@@ -141,7 +182,7 @@ func (w *MicroWebhook) CommandAndReportResults(r *mdm.Request, results *mdm.Comm
 			Params:       r.Params,
 		},
 	}
-	return nil, postWebhookEvent(r.Context, w.client, w.url, ev)
+	return nil, w.enqueue(r.Context, ev)
 }
 
 func (w *MicroWebhook) DeclarativeManagement(r *mdm.Request, m *mdm.DeclarativeManagement) ([]byte, error) {
@@ -157,5 +198,5 @@ func (w *MicroWebhook) DeclarativeManagement(r *mdm.Request, m *mdm.DeclarativeM
 			Params:       r.Params,
 		},
 	}
-	return nil, postWebhookEvent(r.Context, w.client, w.url, ev)
+	return nil, w.enqueue(r.Context, ev)
 }
@@ -0,0 +1,58 @@
+package microwebhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strconv"
+	"time"
+)
+
+// Signature is a single named signature produced by a Signer, carrying
+// the scheme/version identifier (e.g. "v1" for HMAC-SHA256) used to
+// build the X-NanoMDM-Signature header.
+type Signature struct {
+	Version string
+	Value   []byte
+}
+
+// Signer computes one or more signatures over a webhook request body for
+// delivery at ts, allowing alternative schemes (e.g. Ed25519) or secret
+// rotation (multiple simultaneously valid signatures) to be plugged in.
+type Signer interface {
+	Sign(body []byte, ts time.Time) ([]Signature, error)
+}
+
+// signedString is the value HMAC signers sign: the Unix timestamp,
+// a literal ".", then the raw body.
+func signedString(body []byte, ts time.Time) []byte {
+	b := make([]byte, 0, 20+1+len(body))
+	b = strconv.AppendInt(b, ts.Unix(), 10)
+	b = append(b, '.')
+	b = append(b, body...)
+	return b
+}
+
+// HMACSigner signs webhook bodies with HMAC-SHA256, using the "v1"
+// version identifier. Secrets holds one or more currently-valid secrets
+// so operators can rotate secrets by briefly signing (and, on the
+// verifying side, accepting) with both the old and new secret.
+type HMACSigner struct {
+	Secrets [][]byte
+}
+
+// NewHMACSigner returns an HMACSigner for the given secret(s). At least
+// one secret must be provided.
+func NewHMACSigner(secrets ...[]byte) *HMACSigner {
+	return &HMACSigner{Secrets: secrets}
+}
+
+func (s *HMACSigner) Sign(body []byte, ts time.Time) ([]Signature, error) {
+	signed := signedString(body, ts)
+	sigs := make([]Signature, 0, len(s.Secrets))
+	for _, secret := range s.Secrets {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		sigs = append(sigs, Signature{Version: "v1", Value: mac.Sum(nil)})
+	}
+	return sigs, nil
+}
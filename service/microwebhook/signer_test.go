@@ -0,0 +1,91 @@
+package microwebhook
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanomdm/service/microwebhook/verify"
+)
+
+// buildHeaderForTest mirrors setSignatureHeaders' header format for a
+// caller-supplied (rather than time.Now()) timestamp, e.g. to test
+// skew rejection.
+func buildHeaderForTest(ts time.Time, sigs []Signature) string {
+	parts := make([]string, 0, len(sigs)+1)
+	parts = append(parts, "t="+strconv.FormatInt(ts.Unix(), 10))
+	for _, sig := range sigs {
+		parts = append(parts, sig.Version+"="+hex.EncodeToString(sig.Value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func signedRequestHeader(t *testing.T, signer Signer, body []byte, keyID string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/webhook", nil)
+	if err := setSignatureHeaders(req, signer, body, keyID); err != nil {
+		t.Fatalf("setSignatureHeaders() error = %v", err)
+	}
+	return req.Header.Get("X-NanoMDM-Signature")
+}
+
+func TestHMACSignerVerifyRoundTrip(t *testing.T) {
+	secret := []byte("s3cr3t")
+	body := []byte(`{"hello":"world"}`)
+	header := signedRequestHeader(t, NewHMACSigner(secret), body, "")
+	if err := verify.HMACSHA256(secret, header, body, time.Minute); err != nil {
+		t.Fatalf("HMACSHA256() error = %v, want nil", err)
+	}
+}
+
+func TestHMACSignerVerifyRoundTripWrongSecret(t *testing.T) {
+	body := []byte(`{"hello":"world"}`)
+	header := signedRequestHeader(t, NewHMACSigner([]byte("right-secret")), body, "")
+	err := verify.HMACSHA256([]byte("wrong-secret"), header, body, time.Minute)
+	if err != verify.ErrInvalidSignature {
+		t.Fatalf("HMACSHA256() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestHMACSignerVerifyRoundTripTamperedBody(t *testing.T) {
+	secret := []byte("s3cr3t")
+	header := signedRequestHeader(t, NewHMACSigner(secret), []byte(`{"hello":"world"}`), "")
+	err := verify.HMACSHA256(secret, header, []byte(`{"hello":"tampered"}`), time.Minute)
+	if err != verify.ErrInvalidSignature {
+		t.Fatalf("HMACSHA256() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// TestHMACSignerVerifyRoundTripRotation exercises rotating secrets: a
+// body signed with both the old and new secret must verify against
+// either one, matching NewHMACSigner's rotation contract.
+func TestHMACSignerVerifyRoundTripRotation(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	body := []byte(`{"rotating":true}`)
+	header := signedRequestHeader(t, NewHMACSigner(oldSecret, newSecret), body, "")
+	for _, secret := range [][]byte{oldSecret, newSecret} {
+		if err := verify.HMACSHA256(secret, header, body, time.Minute); err != nil {
+			t.Errorf("HMACSHA256() with secret %q error = %v, want nil", secret, err)
+		}
+	}
+}
+
+func TestHMACSignerVerifyRoundTripSkewExceeded(t *testing.T) {
+	secret := []byte("s3cr3t")
+	body := []byte(`{"hello":"world"}`)
+	signer := NewHMACSigner(secret)
+	ts := time.Now().Add(-time.Hour)
+	sigs, err := signer.Sign(body, ts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	header := buildHeaderForTest(ts, sigs)
+	err = verify.HMACSHA256(secret, header, body, time.Minute)
+	if err != verify.ErrTimestampSkew {
+		t.Fatalf("HMACSHA256() error = %v, want ErrTimestampSkew", err)
+	}
+}
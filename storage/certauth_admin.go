@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCertHashNotAssociated is returned by a CertAuthAdminStore's
+// DissociateCertHash when hash is not currently associated with
+// enrollID.
+var ErrCertHashNotAssociated = errors.New("storage: cert hash not associated with enrollment")
+
+// CertAssoc describes one cert-hash association recorded for an
+// enrollment, for operator-facing audit views.
+type CertAssoc struct {
+	Hash      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	// Dissociated is true if this association has since been cleared
+	// (via DissociateCertHash or DissociateAllCertHashes) and is
+	// retained only for audit history.
+	Dissociated bool
+}
+
+// CertAuthAdminStore extends CertAuthStore with operator-driven
+// administrative operations: clearing an enrollment's cert-hash
+// association(s) so that a subsequent TokenUpdate can re-associate with
+// a new (e.g. rotated) certificate, and listing known associations for
+// audit. Unlike CertAuthStore, these are not called as part of normal
+// MDM check-in traffic, so they take a plain enrollment ID and context
+// rather than an *mdm.Request.
+//
+// CertAuthAdminStore must be implemented by the same concrete store as
+// the CertAuthStore it administers (see storage/file.CertAuthStore):
+// dissociating here has to be visible to that CertAuthStore's own
+// HasCertHash/IsCertHashAssociated/EnrollmentHasCertHash checks, or
+// clearing an association never actually unblocks the re-association it
+// exists to permit.
+type CertAuthAdminStore interface {
+	// DissociateCertHash clears enrollID's association with hash,
+	// permitting that cert hash to be associated with enrollID
+	// again via a subsequent Authenticate or (if retroactive
+	// association is enabled) TokenUpdate. It returns
+	// ErrCertHashNotAssociated if hash is not currently associated.
+	DissociateCertHash(ctx context.Context, enrollID string, hash string) error
+
+	// DissociateAllCertHashes clears every cert-hash association
+	// recorded for enrollID.
+	DissociateAllCertHashes(ctx context.Context, enrollID string) error
+
+	// ListCertHashes returns every cert-hash association recorded for
+	// enrollID, including ones since dissociated from it.
+	ListCertHashes(ctx context.Context, enrollID string) ([]CertAssoc, error)
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "context"
+
+// WebhookDeadLetterStore spools webhook events that could not be delivered
+// after all retries were exhausted, so that they can be inspected or
+// re-delivered out-of-band.
+type WebhookDeadLetterStore interface {
+	// StoreDeadLetter persists a failed webhook event. id should uniquely
+	// identify the event (e.g. its EventID) and body is the raw JSON body
+	// that was (attempted to be) POSTed. lastErr is the error from the
+	// final delivery attempt, if any.
+	StoreDeadLetter(ctx context.Context, id string, body []byte, lastErr error) error
+}
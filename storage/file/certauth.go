@@ -0,0 +1,252 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jessepeterson/nanomdm/mdm"
+	"github.com/micromdm/nanomdm/storage"
+)
+
+// CertAuthStore persists cert-hash associations as one JSON file per
+// enrollment ID under Dir, plus a hash-to-enrollment index for
+// cross-enrollment lookups. It satisfies both certauth's CertAuthStore
+// (the association checks consulted on every check-in) and
+// storage.CertAuthAdminStore (the operator-driven endpoints
+// certauth.AdminHandler exposes), backed by the same data, so that
+// DissociateCertHash/DissociateAllCertHashes take effect immediately on
+// the next check-in rather than only in a disconnected audit log.
+type CertAuthStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewCertAuthStore creates a CertAuthStore rooted at dir, creating it if
+// it does not already exist.
+func NewCertAuthStore(dir string) (*CertAuthStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &CertAuthStore{Dir: dir}, nil
+}
+
+func (s *CertAuthStore) path(enrollID string) string {
+	return filepath.Join(s.Dir, enrollID+".json")
+}
+
+func (s *CertAuthStore) load(enrollID string) ([]storage.CertAssoc, error) {
+	body, err := os.ReadFile(s.path(enrollID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var assocs []storage.CertAssoc
+	if err := json.Unmarshal(body, &assocs); err != nil {
+		return nil, err
+	}
+	return assocs, nil
+}
+
+func (s *CertAuthStore) save(enrollID string, assocs []storage.CertAssoc) error {
+	body, err := json.MarshalIndent(assocs, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(enrollID), body, 0644)
+}
+
+// hashIndexPath is a single file recording which enrollment, if any,
+// currently holds an active association with each cert hash, so
+// HasCertHash can answer "has any enrollment used this cert" without
+// scanning every enrollment's file.
+func (s *CertAuthStore) hashIndexPath() string {
+	return filepath.Join(s.Dir, "_hash_index.json")
+}
+
+func (s *CertAuthStore) loadHashIndex() (map[string]string, error) {
+	body, err := os.ReadFile(s.hashIndexPath())
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx := make(map[string]string)
+	if err := json.Unmarshal(body, &idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (s *CertAuthStore) saveHashIndex(idx map[string]string) error {
+	body, err := json.MarshalIndent(idx, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.hashIndexPath(), body, 0644)
+}
+
+// HasCertHash implements certauth's CertAuthStore: it reports whether
+// hash is actively associated with any enrollment, not just r's.
+func (s *CertAuthStore) HasCertHash(r *mdm.Request, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.loadHashIndex()
+	if err != nil {
+		return false, err
+	}
+	_, ok := idx[hash]
+	return ok, nil
+}
+
+// IsCertHashAssociated implements certauth's CertAuthStore: it reports
+// whether r's enrollment is actively associated with hash specifically.
+func (s *CertAuthStore) IsCertHashAssociated(r *mdm.Request, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assocs, err := s.load(r.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range assocs {
+		if a.Hash == hash && !a.Dissociated {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EnrollmentHasCertHash implements certauth's CertAuthStore: it reports
+// whether r's enrollment has any active cert-hash association at all,
+// used to block associating a second hash without an operator first
+// clearing the first via DissociateCertHash.
+func (s *CertAuthStore) EnrollmentHasCertHash(r *mdm.Request, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assocs, err := s.load(r.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range assocs {
+		if !a.Dissociated {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AssociateCertHash implements certauth's CertAuthStore: it records that
+// r's enrollment is now associated with hash, both in r's own
+// association history and in the cross-enrollment index HasCertHash
+// consults.
+func (s *CertAuthStore) AssociateCertHash(r *mdm.Request, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assocs, err := s.load(r.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	found := false
+	for i := range assocs {
+		if assocs[i].Hash == hash {
+			assocs[i].LastSeen = now
+			assocs[i].Dissociated = false
+			found = true
+			break
+		}
+	}
+	if !found {
+		assocs = append(assocs, storage.CertAssoc{Hash: hash, FirstSeen: now, LastSeen: now})
+	}
+	if err := s.save(r.ID, assocs); err != nil {
+		return err
+	}
+	idx, err := s.loadHashIndex()
+	if err != nil {
+		return err
+	}
+	idx[hash] = r.ID
+	return s.saveHashIndex(idx)
+}
+
+// DissociateCertHash implements storage.CertAuthAdminStore, clearing the
+// association CertAuthStore's own checks above consult.
+func (s *CertAuthStore) DissociateCertHash(_ context.Context, enrollID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assocs, err := s.load(enrollID)
+	if err != nil {
+		return err
+	}
+	for i := range assocs {
+		if assocs[i].Hash == hash && !assocs[i].Dissociated {
+			assocs[i].Dissociated = true
+			if err := s.save(enrollID, assocs); err != nil {
+				return err
+			}
+			return s.clearHashIndexEntry(hash, enrollID)
+		}
+	}
+	return fmt.Errorf("%w: %s", storage.ErrCertHashNotAssociated, hash)
+}
+
+// DissociateAllCertHashes implements storage.CertAuthAdminStore.
+func (s *CertAuthStore) DissociateAllCertHashes(_ context.Context, enrollID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assocs, err := s.load(enrollID)
+	if err != nil {
+		return err
+	}
+	idx, err := s.loadHashIndex()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range assocs {
+		if !assocs[i].Dissociated {
+			assocs[i].Dissociated = true
+			if idx[assocs[i].Hash] == enrollID {
+				delete(idx, assocs[i].Hash)
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := s.save(enrollID, assocs); err != nil {
+		return err
+	}
+	return s.saveHashIndex(idx)
+}
+
+// ListCertHashes implements storage.CertAuthAdminStore.
+func (s *CertAuthStore) ListCertHashes(_ context.Context, enrollID string) ([]storage.CertAssoc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(enrollID)
+}
+
+// clearHashIndexEntry removes hash's index entry if it still points at
+// enrollID. Callers must hold s.mu.
+func (s *CertAuthStore) clearHashIndexEntry(hash, enrollID string) error {
+	idx, err := s.loadHashIndex()
+	if err != nil {
+		return err
+	}
+	if idx[hash] != enrollID {
+		return nil
+	}
+	delete(idx, hash)
+	return s.saveHashIndex(idx)
+}
@@ -0,0 +1,68 @@
+package file
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jessepeterson/nanomdm/mdm"
+)
+
+func TestCertAuthStoreDissociateUnblocksReAssociation(t *testing.T) {
+	s, err := NewCertAuthStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCertAuthStore: %v", err)
+	}
+	r := &mdm.Request{ID: "enroll1"}
+
+	if err := s.AssociateCertHash(r, "hash-a"); err != nil {
+		t.Fatalf("AssociateCertHash: %v", err)
+	}
+	if ok, err := s.IsCertHashAssociated(r, "hash-a"); err != nil || !ok {
+		t.Fatalf("IsCertHashAssociated(hash-a) = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := s.EnrollmentHasCertHash(r, "hash-a"); err != nil || !ok {
+		t.Fatalf("EnrollmentHasCertHash = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := s.HasCertHash(r, "hash-a"); err != nil || !ok {
+		t.Fatalf("HasCertHash(hash-a) = %v, %v, want true, nil", ok, err)
+	}
+
+	// This is the bug the admin endpoints exist to fix: after an
+	// operator dissociates, CertAuthStore's own checks (the ones
+	// validateAssociateExistingEnrollment actually consults) must
+	// reflect it immediately, not just a parallel audit log.
+	if err := s.DissociateCertHash(context.Background(), "enroll1", "hash-a"); err != nil {
+		t.Fatalf("DissociateCertHash: %v", err)
+	}
+	if ok, err := s.EnrollmentHasCertHash(r, "hash-a"); err != nil || ok {
+		t.Fatalf("EnrollmentHasCertHash after dissociate = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := s.HasCertHash(r, "hash-a"); err != nil || ok {
+		t.Fatalf("HasCertHash after dissociate = %v, %v, want false, nil", ok, err)
+	}
+
+	if err := s.AssociateCertHash(r, "hash-b"); err != nil {
+		t.Fatalf("re-associate AssociateCertHash: %v", err)
+	}
+	if ok, err := s.IsCertHashAssociated(r, "hash-b"); err != nil || !ok {
+		t.Fatalf("IsCertHashAssociated(hash-b) after re-associate = %v, %v, want true, nil", ok, err)
+	}
+
+	assocs, err := s.ListCertHashes(context.Background(), "enroll1")
+	if err != nil {
+		t.Fatalf("ListCertHashes: %v", err)
+	}
+	if len(assocs) != 2 {
+		t.Fatalf("ListCertHashes returned %d associations, want 2 (including dissociated hash-a)", len(assocs))
+	}
+}
+
+func TestCertAuthStoreDissociateUnknownHash(t *testing.T) {
+	s, err := NewCertAuthStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCertAuthStore: %v", err)
+	}
+	if err := s.DissociateCertHash(context.Background(), "enroll1", "nope"); err == nil {
+		t.Fatal("DissociateCertHash for an unassociated hash should error")
+	}
+}
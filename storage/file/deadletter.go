@@ -0,0 +1,47 @@
+// Package file contains simple filesystem-backed storage implementations.
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DeadLetterStore spools undeliverable webhook events to individual files
+// under Dir. It satisfies storage.WebhookDeadLetterStore.
+type DeadLetterStore struct {
+	Dir string
+}
+
+// NewDeadLetterStore creates a DeadLetterStore rooted at dir, creating it
+// if it does not already exist.
+func NewDeadLetterStore(dir string) (*DeadLetterStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DeadLetterStore{Dir: dir}, nil
+}
+
+// StoreDeadLetter writes body to a file named after id and the current
+// time so that repeated failures for the same event don't clobber one
+// another.
+func (s *DeadLetterStore) StoreDeadLetter(_ context.Context, id string, body []byte, lastErr error) error {
+	name := fmt.Sprintf("%s.%d.json", id, time.Now().UnixNano())
+	path := filepath.Join(s.Dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(body); err != nil {
+		return err
+	}
+	if lastErr != nil {
+		if _, err := fmt.Fprintf(f, "\n// last error: %s\n", lastErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}